@@ -0,0 +1,44 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "strings"
+
+// extractFqName parses the fqName out of a prometheus.Desc.String() output.
+// prometheus.Desc does not expose its fqName directly, so RemoteWriter uses
+// this to build the `__name__` label the same way the pull path's metric
+// names are derived.
+func extractFqName(descStr string) string {
+	return extractDescField(descStr, `fqName: "`)
+}
+
+// extractHelp parses the help text out of a prometheus.Desc.String() output.
+func extractHelp(descStr string) string {
+	return extractDescField(descStr, `help: "`)
+}
+
+// extractDescField pulls the quoted value following prefix out of a
+// prometheus.Desc.String() output, e.g. `fqName: "..."` or `help: "..."`.
+func extractDescField(descStr, prefix string) string {
+	i := strings.Index(descStr, prefix)
+	if i < 0 {
+		return "unknown"
+	}
+	s := descStr[i+len(prefix):]
+	j := strings.Index(s, `"`)
+	if j < 0 {
+		return "unknown"
+	}
+	return s[:j]
+}
@@ -0,0 +1,210 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+)
+
+// HistogramAttributeConfig maps a gjson path yielding multiple samples in a
+// single collection (e.g. the per-sensor readings in
+// nvme_smart_health_information_log.temperature_sensors[]) onto a native
+// histogram bucket schema. Attributes that only ever produce one sample per
+// collection (a single ATA attribute's raw value, say) aren't distribution-
+// shaped within a single JSON payload and stay scalar gauges.
+type HistogramAttributeConfig struct {
+	// JSONPath is a gjson path to an array of numeric samples.
+	JSONPath string
+
+	MetricName string
+	Help       string
+
+	// Schema is the native histogram resolution schema factor, valid from
+	// -4 to 8 inclusive. Higher values give finer-grained, log-linear
+	// buckets at the cost of more buckets per observation.
+	Schema int32
+	// ZeroThreshold is the width of the zero bucket.
+	ZeroThreshold float64
+}
+
+// nativeHistogramAccumulator accumulates raw SMART sample values into
+// exponential (base-2 log-linear) buckets per the native histogram schema,
+// ready to be handed to prometheus.NewConstNativeHistogram.
+type nativeHistogramAccumulator struct {
+	cfg HistogramAttributeConfig
+
+	count uint64
+	sum   float64
+	zero  uint64
+	pos   map[int]uint64
+	neg   map[int]uint64
+}
+
+func newNativeHistogramAccumulator(cfg HistogramAttributeConfig) *nativeHistogramAccumulator {
+	return &nativeHistogramAccumulator{
+		cfg: cfg,
+		pos: make(map[int]uint64),
+		neg: make(map[int]uint64),
+	}
+}
+
+// Observe adds v to the accumulator, bucketing it according to the
+// configured schema.
+func (a *nativeHistogramAccumulator) Observe(v float64) {
+	a.count++
+	a.sum += v
+
+	if math.Abs(v) <= a.cfg.ZeroThreshold {
+		a.zero++
+		return
+	}
+
+	idx := bucketIndex(math.Abs(v), a.cfg.Schema)
+	if v > 0 {
+		a.pos[idx]++
+	} else {
+		a.neg[idx]++
+	}
+}
+
+// bucketIndex returns the index of the exponential bucket that v falls into
+// for the given schema factor, following the native histogram convention
+// where bucket boundaries are base^index with base = 2^(2^-schema).
+func bucketIndex(v float64, schema int32) int {
+	base := math.Pow(2, math.Pow(2, float64(-schema)))
+	return int(math.Ceil(math.Log(v) / math.Log(base)))
+}
+
+// Metric builds the prometheus.Metric for this accumulator's observations,
+// using desc and labelValues to identify the device the same way the pull
+// path's scalar gauges do.
+func (a *nativeHistogramAccumulator) Metric(desc *prometheus.Desc, labelValues ...string) (prometheus.Metric, error) {
+	return prometheus.NewConstNativeHistogram(
+		desc,
+		a.count,
+		a.sum,
+		bucketCounts(a.pos),
+		bucketCounts(a.neg),
+		a.zero,
+		a.cfg.Schema,
+		a.cfg.ZeroThreshold,
+		time.Time{},
+		labelValues...,
+	)
+}
+
+// bucketCounts converts absolute per-bucket counts into the map[int]int64
+// form prometheus.NewConstNativeHistogram takes. The counts stay absolute:
+// NewConstNativeHistogram builds the spans and delta-encoded counts native
+// histograms store on the wire itself.
+func bucketCounts(buckets map[int]uint64) map[int]int64 {
+	if len(buckets) == 0 {
+		return nil
+	}
+	out := make(map[int]int64, len(buckets))
+	for idx, count := range buckets {
+		out[idx] = int64(count)
+	}
+	return out
+}
+
+// defaultHistogramAttributes lists the NVMe SMART log fields this collector
+// treats as distributions rather than scalar gauges. Today that's just the
+// multi-sensor temperature array; other candidates mentioned in the
+// feature request (wear, workload rate) report a single value per
+// collection and need historical state to be distribution-shaped, which is
+// out of scope here.
+var defaultHistogramAttributes = []HistogramAttributeConfig{
+	{
+		JSONPath:      "nvme_smart_health_information_log.temperature_sensors",
+		MetricName:    "smartctl_device_nvme_temperature_sensor_distribution_celsius",
+		Help:          "Distribution of NVMe temperature sensor samples within a single collection.",
+		Schema:        3,
+		ZeroThreshold: 0.5,
+	},
+}
+
+// NativeHistogramCollector emits native histogram metrics for the
+// multi-sample NVMe log fields configured in attrs. It is meant to be
+// registered alongside SMARTctl for devices whose JSON carries one of those
+// fields, rather than folded into SMARTctl.Collect, so a device without any
+// distribution-shaped fields pays no cost and existing scalar-gauge
+// descriptors are undisturbed.
+//
+// NOTE: that registration (wiring this into main's collector setup, behind
+// a flag/config option) is not part of this change; today it's exercised
+// only by histogram_test.go. Follow-up work needs to register it from
+// main before it's operator-reachable.
+type NativeHistogramCollector struct {
+	logger      *slog.Logger
+	json        gjson.Result
+	attrs       []HistogramAttributeConfig
+	labelNames  []string
+	labelValues []string
+}
+
+// NewNativeHistogramCollector builds a NativeHistogramCollector over json
+// using attrs, attaching labelValues (e.g. device/model) to every emitted
+// histogram identically to how SMARTctl labels its scalar gauges.
+func NewNativeHistogramCollector(logger *slog.Logger, json gjson.Result, attrs []HistogramAttributeConfig, labelNames, labelValues []string) *NativeHistogramCollector {
+	return &NativeHistogramCollector{
+		logger:      logger,
+		json:        json,
+		attrs:       attrs,
+		labelNames:  labelNames,
+		labelValues: labelValues,
+	}
+}
+
+// Describe sends one descriptor per configured attribute; the metric set is
+// fixed at construction time so, unlike UncheckedCollector, this collector
+// can (and should) describe itself.
+func (c *NativeHistogramCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, attr := range c.attrs {
+		ch <- prometheus.NewDesc(attr.MetricName, attr.Help, c.labelNames, nil)
+	}
+}
+
+// Collect reads each configured attribute's sample array out of the device
+// JSON and emits a native histogram over those samples.
+func (c *NativeHistogramCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, attr := range c.attrs {
+		result := c.json.Get(attr.JSONPath)
+		if !result.Exists() || !result.IsArray() {
+			continue
+		}
+
+		acc := newNativeHistogramAccumulator(attr)
+		result.ForEach(func(_, value gjson.Result) bool {
+			acc.Observe(value.Float())
+			return true
+		})
+		if acc.count == 0 {
+			continue
+		}
+
+		desc := prometheus.NewDesc(attr.MetricName, attr.Help, c.labelNames, nil)
+		metric, err := acc.Metric(desc, c.labelValues...)
+		if err != nil {
+			c.logger.Warn("building native histogram", "metric", attr.MetricName, "err", err)
+			continue
+		}
+		ch <- metric
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/tidwall/gjson"
+)
+
+// TestNativeHistogramGoldenFiles exercises defaultHistogramAttributes end
+// to end: it drives NativeHistogramCollector, as a registration alongside
+// SMARTctl would for a device whose JSON carries one of the configured
+// multi-sample fields, over every testdata/histogram/*.json fixture and
+// checks the result against a golden file.
+//
+// These fixtures live under testdata/histogram/ rather than testdata/ so
+// TestGoldenFiles' testdata/*.json glob (the pull-path/remote-write golden
+// pipeline) doesn't also pick them up and fail for lack of a matching
+// .golden/.rw.golden pair.
+func TestNativeHistogramGoldenFiles(t *testing.T) {
+	jsonFiles, err := filepath.Glob("testdata/histogram/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jsonFiles) == 0 {
+		t.Fatal("no testdata/histogram/*.json files found")
+	}
+
+	for _, jsonFile := range jsonFiles {
+		basename := filepath.Base(jsonFile)
+		goldenFile := filepath.Join("testdata", "histogram", "golden", strings.TrimSuffix(basename, ".json")+".golden")
+
+		t.Run(basename, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(jsonFile)
+			if err != nil {
+				t.Fatalf("reading %s: %v", jsonFile, err)
+			}
+
+			json := gjson.Parse(string(data))
+			collector := NewNativeHistogramCollector(slog.Default(), json, defaultHistogramAttributes,
+				[]string{"device"}, []string{"nvme0"})
+
+			ch := make(chan prometheus.Metric, 100)
+			collector.Collect(ch)
+			close(ch)
+
+			var lines []string
+			var sawHistogram bool
+			for m := range ch {
+				metric := &dto.Metric{}
+				if err := m.Write(metric); err != nil {
+					t.Fatalf("failed to write metric: %v", err)
+				}
+				if metric.Histogram != nil {
+					sawHistogram = true
+				}
+				lines = append(lines, formatMetric(m.Desc(), metric))
+			}
+			if !sawHistogram {
+				t.Fatalf("expected at least one native histogram metric from %s, got none", jsonFile)
+			}
+			sort.Strings(lines)
+			got := strings.Join(lines, "\n") + "\n"
+
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Join("testdata", "histogram", "golden"), 0755); err != nil {
+					t.Fatalf("creating golden dir: %v", err)
+				}
+				if err := os.WriteFile(goldenFile, []byte(got), 0644); err != nil {
+					t.Fatalf("writing golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("reading golden file %s: %v (run with -update to create)", goldenFile, err)
+			}
+			if string(want) != got {
+				t.Errorf("output mismatch for %s:\n%s", basename, diff(string(want), got))
+			}
+		})
+	}
+}
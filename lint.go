@@ -0,0 +1,185 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// metricNameRE and labelNameRE mirror the character classes Prometheus
+// itself enforces for metric and label names.
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// forbiddenUnitSuffixes maps non-base unit suffixes to the base unit a
+// metric name should use instead, per Prometheus naming conventions.
+var forbiddenUnitSuffixes = map[string]string{
+	"_milliseconds": "_seconds",
+	"_microseconds": "_seconds",
+	"_kilobytes":    "_bytes",
+	"_megabytes":    "_bytes",
+	"_fahrenheit":   "_celsius",
+}
+
+// LintProblem describes a single validation failure for one metric.
+type LintProblem struct {
+	Metric    string
+	Validator string
+	Message   string
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("%s: [%s] %s", p.Metric, p.Validator, p.Message)
+}
+
+// LintMetric describes the subset of a collected metric's identity that the
+// linter rules need: its fully qualified name, HELP text, type and label
+// names. It is deliberately decoupled from prometheus.Metric/dto.Metric so
+// rules can be unit tested against literal values.
+type LintMetric struct {
+	FQName     string
+	Help       string
+	Type       dto.MetricType
+	LabelNames []string
+}
+
+// lintRule validates a single LintMetric, returning a human readable
+// message per problem found (empty if none).
+type lintRule struct {
+	name  string
+	check func(LintMetric) []string
+}
+
+// Linter runs a fixed set of promlint-style rules against collected
+// metrics, modeled on prometheus/client_golang's testutil/promlint. Metric
+// names present in the allowlist are exempt, for legacy names that predate
+// these rules.
+type Linter struct {
+	allowlist map[string]bool
+	rules     []lintRule
+}
+
+// NewLinter builds a Linter with the default rule set and the given
+// allowlist of fqNames to exempt from all rules.
+func NewLinter(allowlist map[string]bool) *Linter {
+	return &Linter{
+		allowlist: allowlist,
+		rules: []lintRule{
+			{name: "metric_name_chars", check: lintMetricNameChars},
+			{name: "label_name_chars", check: lintLabelNameChars},
+			{name: "help_text", check: lintHelpText},
+			{name: "base_unit", check: lintBaseUnit},
+			{name: "counter_total", check: lintCounterTotal},
+			{name: "reserved_labels", check: lintReservedLabels},
+		},
+	}
+}
+
+// Lint runs every rule against m, skipping m entirely if its name is
+// allowlisted.
+func (l *Linter) Lint(m LintMetric) []LintProblem {
+	if l.allowlist[m.FQName] {
+		return nil
+	}
+
+	var problems []LintProblem
+	for _, rule := range l.rules {
+		for _, msg := range rule.check(m) {
+			problems = append(problems, LintProblem{Metric: m.FQName, Validator: rule.name, Message: msg})
+		}
+	}
+	return problems
+}
+
+func lintMetricNameChars(m LintMetric) []string {
+	if !metricNameRE.MatchString(m.FQName) {
+		return []string{fmt.Sprintf("metric name %q contains characters outside [a-zA-Z0-9_:]", m.FQName)}
+	}
+	return nil
+}
+
+func lintLabelNameChars(m LintMetric) []string {
+	var problems []string
+	for _, name := range m.LabelNames {
+		if !labelNameRE.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("label name %q contains characters outside [a-zA-Z0-9_]", name))
+		}
+	}
+	return problems
+}
+
+func lintHelpText(m LintMetric) []string {
+	if strings.TrimSpace(m.Help) == "" {
+		return []string{"metric has no HELP text"}
+	}
+	return nil
+}
+
+func lintBaseUnit(m LintMetric) []string {
+	for suffix, baseUnit := range forbiddenUnitSuffixes {
+		if strings.HasSuffix(m.FQName, suffix) {
+			return []string{fmt.Sprintf("metric name uses non-base unit suffix %q, use %q instead", suffix, baseUnit)}
+		}
+	}
+	return nil
+}
+
+func lintCounterTotal(m LintMetric) []string {
+	if m.Type == dto.MetricType_COUNTER && !strings.HasSuffix(m.FQName, "_total") {
+		return []string{"counter metric name should have a \"_total\" suffix"}
+	}
+	return nil
+}
+
+// loadAllowlist reads a newline-delimited allowlist file of fqNames exempt
+// from lint rules. Blank lines and lines starting with "#" are ignored.
+func loadAllowlist(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening allowlist %s: %w", path, err)
+	}
+	defer f.Close()
+
+	allowlist := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		allowlist[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading allowlist %s: %w", path, err)
+	}
+	return allowlist, nil
+}
+
+func lintReservedLabels(m LintMetric) []string {
+	var problems []string
+	for _, name := range m.LabelNames {
+		if name == "le" || name == "quantile" {
+			problems = append(problems, fmt.Sprintf("label name %q is reserved for histogram/summary buckets and quantiles", name))
+		}
+	}
+	return problems
+}
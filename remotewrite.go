@@ -0,0 +1,347 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	remoteWriteVersionHeader = "X-Prometheus-Remote-Write-Version"
+	remoteWriteVersion       = "0.1.0"
+	remoteWriteContentType   = "application/x-protobuf"
+)
+
+// RemoteWriteConfig configures a Prometheus Remote Write push target. It
+// mirrors the shape of Prometheus Agent's `remote_write` block so operators
+// can reuse familiar field names in the exporter's YAML config.
+type RemoteWriteConfig struct {
+	URL            string            `yaml:"url"`
+	Timeout        time.Duration     `yaml:"timeout"`
+	Headers        map[string]string `yaml:"headers"`
+	BearerToken    string            `yaml:"bearer_token"`
+	BasicAuthUser  string            `yaml:"basic_auth_user"`
+	BasicAuthPass  string            `yaml:"basic_auth_pass"`
+	ExternalLabels map[string]string `yaml:"external_labels"`
+	BatchSize      int               `yaml:"batch_size"`
+	QueueSize      int               `yaml:"queue_size"`
+	MaxRetries     int               `yaml:"max_retries"`
+	TTL            time.Duration     `yaml:"ttl"`
+	TLSInsecure    bool              `yaml:"tls_insecure_skip_verify"`
+}
+
+func (c RemoteWriteConfig) withDefaults() RemoteWriteConfig {
+	if c.Timeout <= 0 {
+		c.Timeout = 30 * time.Second
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 500
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10000
+	}
+	if c.MaxRetries <= 0 {
+		c.MaxRetries = 5
+	}
+	if c.TTL <= 0 {
+		c.TTL = 5 * time.Minute
+	}
+	return c
+}
+
+// RemoteWriter pushes collected metrics to a Prometheus Remote Write
+// endpoint on a fixed interval, for deployments where the exporter cannot be
+// scraped directly (NAT'd edge hosts, one-shot cron batch runs). Collection
+// ticks enqueue onto a bounded channel (capacity cfg.QueueSize) that a
+// drain goroutine pushes from, so a slow/retrying endpoint cannot stall the
+// next collection tick.
+//
+// NOTE: wiring a RemoteWriter up from main (reading RemoteWriteConfig out
+// of the exporter's YAML config, starting Run alongside the pull-path
+// server) is follow-up work and not part of this change; today it's
+// exercised only by remotewrite_test.go.
+type RemoteWriter struct {
+	cfg    RemoteWriteConfig
+	logger *slog.Logger
+	client *http.Client
+	queue  chan []prompb.TimeSeries
+}
+
+// NewRemoteWriter builds a RemoteWriter from the given config, filling in
+// defaults for any unset batching/retry/TTL fields.
+func NewRemoteWriter(logger *slog.Logger, cfg RemoteWriteConfig) *RemoteWriter {
+	cfg = cfg.withDefaults()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: cfg.TLSInsecure}
+	return &RemoteWriter{
+		cfg:    cfg,
+		logger: logger,
+		client: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		queue:  make(chan []prompb.TimeSeries, cfg.QueueSize),
+	}
+}
+
+// Run calls collect on the given interval and enqueues the result for
+// delivery, until ctx is cancelled. Delivery happens on a separate drain
+// goroutine (see drainQueue) so a push blocked on retries/backoff never
+// delays the next collection tick.
+func (w *RemoteWriter) Run(ctx context.Context, interval time.Duration, collect func() ([]prompb.TimeSeries, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	go w.drainQueue(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			series, err := collect()
+			if err != nil {
+				w.logger.Error("collecting SMART metrics for remote write", "err", err)
+				continue
+			}
+			w.enqueue(series)
+		}
+	}
+}
+
+// drainQueue pushes queued batches to the remote write endpoint one at a
+// time until ctx is cancelled. It runs on its own goroutine, started by
+// Run, so a slow push never blocks Run's ticker loop from enqueuing the
+// next collection.
+func (w *RemoteWriter) drainQueue(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case series := <-w.queue:
+			if err := w.Push(ctx, series); err != nil {
+				w.logger.Error("pushing to remote write endpoint", "err", err)
+			}
+		}
+	}
+}
+
+// enqueue buffers series for delivery by drainQueue. If the queue is
+// already at cfg.QueueSize capacity, the batch is dropped and logged rather
+// than blocking the next collection tick.
+func (w *RemoteWriter) enqueue(series []prompb.TimeSeries) {
+	select {
+	case w.queue <- series:
+	default:
+		w.logger.Warn("remote write queue full, dropping collected batch", "queue_size", w.cfg.QueueSize)
+	}
+}
+
+// Push sends series to the configured remote write endpoint in batches of
+// cfg.BatchSize, dropping any sample older than cfg.TTL, retrying 5xx/429
+// responses with exponential backoff honoring Retry-After.
+func (w *RemoteWriter) Push(ctx context.Context, series []prompb.TimeSeries) error {
+	series = w.dropExpired(series)
+
+	for start := 0; start < len(series); start += w.cfg.BatchSize {
+		end := start + w.cfg.BatchSize
+		if end > len(series) {
+			end = len(series)
+		}
+		if err := w.pushBatch(ctx, series[start:end]); err != nil {
+			return fmt.Errorf("pushing batch [%d:%d]: %w", start, end, err)
+		}
+	}
+	return nil
+}
+
+func (w *RemoteWriter) dropExpired(series []prompb.TimeSeries) []prompb.TimeSeries {
+	if w.cfg.TTL <= 0 {
+		return series
+	}
+	cutoff := time.Now().Add(-w.cfg.TTL).UnixMilli()
+	kept := series[:0]
+	for _, ts := range series {
+		samples := ts.Samples[:0]
+		for _, s := range ts.Samples {
+			if s.Timestamp >= cutoff {
+				samples = append(samples, s)
+			}
+		}
+		if len(samples) > 0 {
+			ts.Samples = samples
+			kept = append(kept, ts)
+		}
+	}
+	return kept
+}
+
+func (w *RemoteWriter) pushBatch(ctx context.Context, series []prompb.TimeSeries) error {
+	req := &prompb.WriteRequest{Timeseries: series}
+	data, err := proto.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshaling write request: %w", err)
+	}
+	compressed := snappy.Encode(nil, data)
+
+	var lastErr error
+	for attempt := 0; attempt < w.cfg.MaxRetries; attempt++ {
+		retryAfter, err := w.send(ctx, compressed)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) {
+			return fmt.Errorf("non-retryable push failure: %w", err)
+		}
+
+		if retryAfter <= 0 {
+			retryAfter = backoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(retryAfter):
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", w.cfg.MaxRetries, lastErr)
+}
+
+// retryableError marks a send failure as safe to retry (5xx/429 responses),
+// as distinct from a permanent failure like 400/401/404 that retrying
+// cannot fix.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// send performs a single POST attempt. On a retryable (5xx/429) response it
+// returns the server's requested Retry-After (or 0 if absent) alongside a
+// *retryableError so the caller can back off and retry; other failures are
+// returned as plain errors so the caller bails out immediately.
+func (w *RemoteWriter) send(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", remoteWriteContentType)
+	req.Header.Set(remoteWriteVersionHeader, remoteWriteVersion)
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+w.cfg.BearerToken)
+	} else if w.cfg.BasicAuthUser != "" {
+		req.SetBasicAuth(w.cfg.BasicAuthUser, w.cfg.BasicAuthPass)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("sending request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 == 2 {
+		return 0, nil
+	}
+
+	retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode/100 == 5 {
+		return retryAfter, &retryableError{err: fmt.Errorf("retryable status %d", resp.StatusCode)}
+	}
+	return 0, fmt.Errorf("non-retryable status %d", resp.StatusCode)
+}
+
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func backoff(attempt int) time.Duration {
+	base := time.Second * time.Duration(1<<attempt)
+	if base > 30*time.Second {
+		base = 30 * time.Second
+	}
+	jitter := time.Duration(rand.Int63n(int64(base) / 2))
+	return base/2 + jitter
+}
+
+// seriesFromMetrics converts collected Prometheus metrics into
+// prompb.TimeSeries, applying cfg.ExternalLabels, for use by Push. The label
+// set on each series mirrors the pull path exactly: metric name as
+// `__name__` plus every label the collector attached to the device.
+func seriesFromMetrics(cfg RemoteWriteConfig, metrics []prometheus.Metric, now time.Time) ([]prompb.TimeSeries, error) {
+	series := make([]prompb.TimeSeries, 0, len(metrics))
+	ts := now.UnixMilli()
+
+	for _, m := range metrics {
+		d := &dto.Metric{}
+		if err := m.Write(d); err != nil {
+			return nil, fmt.Errorf("writing metric: %w", err)
+		}
+
+		var v float64
+		switch {
+		case d.Gauge != nil:
+			v = d.GetGauge().GetValue()
+		case d.Counter != nil:
+			v = d.GetCounter().GetValue()
+		case d.Untyped != nil:
+			v = d.GetUntyped().GetValue()
+		default:
+			continue
+		}
+
+		labels := []prompb.Label{{Name: "__name__", Value: extractFqName(m.Desc().String())}}
+		for _, lp := range d.GetLabel() {
+			labels = append(labels, prompb.Label{Name: lp.GetName(), Value: lp.GetValue()})
+		}
+		for k, v := range cfg.ExternalLabels {
+			labels = append(labels, prompb.Label{Name: k, Value: v})
+		}
+
+		series = append(series, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: v, Timestamp: ts}},
+		})
+	}
+	return series, nil
+}
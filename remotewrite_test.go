@@ -0,0 +1,107 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/prompb"
+)
+
+func testSeries(name string) []prompb.TimeSeries {
+	return []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: name}},
+		// A current timestamp keeps Push's TTL filter from dropping the sample.
+		Samples: []prompb.Sample{{Value: 1, Timestamp: time.Now().UnixMilli()}},
+	}}
+}
+
+// TestRemoteWriterRetriesOn5xx verifies Push retries a 503 response and
+// succeeds once the endpoint recovers.
+func TestRemoteWriterRetriesOn5xx(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rw := NewRemoteWriter(slog.Default(), RemoteWriteConfig{URL: srv.URL, MaxRetries: 3})
+	if err := rw.Push(context.Background(), testSeries("smartctl_test_metric")); err != nil {
+		t.Fatalf("Push returned error after recovering: %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests (1 failure + 1 success), got %d", got)
+	}
+}
+
+// TestRemoteWriterNoRetryOn4xx verifies Push does not retry a permanent
+// client error like 400.
+func TestRemoteWriterNoRetryOn4xx(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	rw := NewRemoteWriter(slog.Default(), RemoteWriteConfig{URL: srv.URL, MaxRetries: 5})
+	if err := rw.Push(context.Background(), testSeries("smartctl_test_metric")); err == nil {
+		t.Fatal("expected Push to return an error for a 400 response")
+	}
+	if got := requests.Load(); got != 1 {
+		t.Errorf("expected exactly 1 request (no retries on 4xx), got %d", got)
+	}
+}
+
+// TestRemoteWriterEnqueueDropsWhenFull verifies enqueue does not block when
+// the queue is at capacity, and drops the newest batch rather than the one
+// already queued.
+func TestRemoteWriterEnqueueDropsWhenFull(t *testing.T) {
+	rw := NewRemoteWriter(slog.Default(), RemoteWriteConfig{URL: "http://example.invalid", QueueSize: 1})
+
+	first := testSeries("smartctl_first")
+	second := testSeries("smartctl_second")
+
+	done := make(chan struct{})
+	go func() {
+		rw.enqueue(first)
+		rw.enqueue(second)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("enqueue blocked instead of dropping the batch that didn't fit")
+	}
+
+	select {
+	case got := <-rw.queue:
+		if got[0].Labels[0].Value != first[0].Labels[0].Value {
+			t.Errorf("expected the first-enqueued batch to survive, got %q", got[0].Labels[0].Value)
+		}
+	default:
+		t.Fatal("expected one batch to remain queued")
+	}
+}
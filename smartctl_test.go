@@ -23,12 +23,18 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
 	"github.com/tidwall/gjson"
 )
 
+// remoteWriteTestTime is the fixed "now" used when building remote-write
+// test payloads so golden files stay deterministic across runs.
+var remoteWriteTestTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
 var updateGolden = flag.Bool("update", false, "update .golden files")
 
 func TestBuildDeviceLabel(t *testing.T) {
@@ -57,9 +63,26 @@ func TestBuildDeviceLabel(t *testing.T) {
 	}
 }
 
-// collectMetrics runs the full Collect() pipeline on JSON data and returns
-// sorted, deterministic metric output.
-func collectMetrics(t *testing.T, jsonData []byte) string {
+// formatMetrics formats already-collected metrics into sorted, deterministic
+// text output.
+func formatMetrics(t *testing.T, metrics []prometheus.Metric) string {
+	t.Helper()
+	var lines []string
+	for _, m := range metrics {
+		metric := &dto.Metric{}
+		if err := m.Write(metric); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+		lines = append(lines, formatMetric(m.Desc(), metric))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// collectRawMetrics runs the full Collect() pipeline on JSON data and
+// returns the un-formatted prometheus.Metric values, so callers can derive
+// the scalar, remote-write and lint views from a single Collect() run.
+func collectRawMetrics(t *testing.T, jsonData []byte) (*SMARTctl, []prometheus.Metric) {
 	t.Helper()
 	json := gjson.Parse(string(jsonData))
 	ch := make(chan prometheus.Metric, 10000)
@@ -67,18 +90,49 @@ func collectMetrics(t *testing.T, jsonData []byte) string {
 	smart.Collect()
 	close(ch)
 
-	var lines []string
+	var metrics []prometheus.Metric
 	for m := range ch {
-		metric := &dto.Metric{}
-		if err := m.Write(metric); err != nil {
-			t.Fatalf("failed to write metric: %v", err)
-		}
-		lines = append(lines, formatMetric(m.Desc(), metric))
+		metrics = append(metrics, m)
+	}
+	return smart, metrics
+}
+
+// formatRemoteWriteSeries converts already-collected metrics into
+// prompb.TimeSeries the same way RemoteWriter.Push would, returning a
+// deterministic, sorted text rendering for golden comparison.
+func formatRemoteWriteSeries(t *testing.T, metrics []prometheus.Metric) string {
+	t.Helper()
+	cfg := RemoteWriteConfig{ExternalLabels: map[string]string{"env": "test"}}
+	series, err := seriesFromMetrics(cfg, metrics, remoteWriteTestTime)
+	if err != nil {
+		t.Fatalf("building remote write series: %v", err)
+	}
+
+	lines := make([]string, 0, len(series))
+	for _, ts := range series {
+		lines = append(lines, formatTimeSeries(ts))
 	}
 	sort.Strings(lines)
 	return strings.Join(lines, "\n") + "\n"
 }
 
+// formatTimeSeries serializes a prompb.TimeSeries to a deterministic text
+// format analogous to formatMetric, for golden comparison.
+func formatTimeSeries(ts prompb.TimeSeries) string {
+	labels := make([]string, 0, len(ts.Labels))
+	for _, l := range ts.Labels {
+		labels = append(labels, fmt.Sprintf("%s=%q", l.Name, l.Value))
+	}
+	sort.Strings(labels)
+
+	samples := make([]string, 0, len(ts.Samples))
+	for _, s := range ts.Samples {
+		samples = append(samples, fmt.Sprintf("%s@%d", strconv.FormatFloat(s.Value, 'g', -1, 64), s.Timestamp))
+	}
+
+	return fmt.Sprintf("{%s} %s", strings.Join(labels, ","), strings.Join(samples, ","))
+}
+
 // formatMetric serializes a single metric to a deterministic text format.
 func formatMetric(desc *prometheus.Desc, m *dto.Metric) string {
 	fqName := extractFqName(desc.String())
@@ -90,6 +144,15 @@ func formatMetric(desc *prometheus.Desc, m *dto.Metric) string {
 	}
 	sort.Strings(labelPairs)
 
+	labelStr := strings.Join(labelPairs, ",")
+
+	if m.Histogram != nil {
+		if labelStr != "" {
+			return fmt.Sprintf("%s{%s} %s", fqName, labelStr, formatNativeHistogram(m.Histogram))
+		}
+		return fmt.Sprintf("%s %s", fqName, formatNativeHistogram(m.Histogram))
+	}
+
 	var v float64
 	switch {
 	case m.Gauge != nil:
@@ -100,26 +163,109 @@ func formatMetric(desc *prometheus.Desc, m *dto.Metric) string {
 		v = m.GetUntyped().GetValue()
 	}
 
-	labelStr := strings.Join(labelPairs, ",")
 	if labelStr != "" {
 		return fmt.Sprintf("%s{%s} %s", fqName, labelStr, strconv.FormatFloat(v, 'g', -1, 64))
 	}
 	return fmt.Sprintf("%s %s", fqName, strconv.FormatFloat(v, 'g', -1, 64))
 }
 
-// extractFqName parses the fqName from a prometheus.Desc.String() output.
-func extractFqName(descStr string) string {
-	const prefix = `fqName: "`
-	i := strings.Index(descStr, prefix)
-	if i < 0 {
-		return "unknown"
+// formatNativeHistogram serializes a native histogram's schema, zero
+// bucket, span offsets/lengths and delta-encoded bucket counts into a
+// deterministic text format for golden comparison.
+func formatNativeHistogram(h *dto.Histogram) string {
+	return fmt.Sprintf(
+		"schema=%d,zero_threshold=%s,zero_count=%d,sample_count=%d,sample_sum=%s,pos_spans=%s,pos_deltas=%v,neg_spans=%s,neg_deltas=%v",
+		h.GetSchema(),
+		strconv.FormatFloat(h.GetZeroThreshold(), 'g', -1, 64),
+		h.GetZeroCount(),
+		h.GetSampleCount(),
+		strconv.FormatFloat(h.GetSampleSum(), 'g', -1, 64),
+		formatSpans(h.GetPositiveSpan()),
+		h.GetPositiveDelta(),
+		formatSpans(h.GetNegativeSpan()),
+		h.GetNegativeDelta(),
+	)
+}
+
+// formatSpans renders bucket span offset/length pairs deterministically.
+func formatSpans(spans []*dto.BucketSpan) string {
+	parts := make([]string, 0, len(spans))
+	for _, s := range spans {
+		parts = append(parts, fmt.Sprintf("(%d,%d)", s.GetOffset(), s.GetLength()))
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// lintMetrics runs every collected metric through the promlint-style Linter
+// and fails t with the offending metric name and validator for each
+// problem found.
+func lintMetrics(t *testing.T, metrics []prometheus.Metric) {
+	t.Helper()
+	allowlist, err := loadAllowlist(filepath.Join("testdata", "lint_allowlist.txt"))
+	if err != nil {
+		t.Fatalf("loading lint allowlist: %v", err)
+	}
+	linter := NewLinter(allowlist)
+
+	for _, m := range metrics {
+		d := &dto.Metric{}
+		if err := m.Write(d); err != nil {
+			t.Fatalf("failed to write metric: %v", err)
+		}
+
+		labelNames := make([]string, 0, len(d.GetLabel()))
+		for _, lp := range d.GetLabel() {
+			labelNames = append(labelNames, lp.GetName())
+		}
+
+		metricType := dto.MetricType_UNTYPED
+		switch {
+		case d.Gauge != nil:
+			metricType = dto.MetricType_GAUGE
+		case d.Counter != nil:
+			metricType = dto.MetricType_COUNTER
+		case d.Histogram != nil:
+			metricType = dto.MetricType_HISTOGRAM
+		}
+
+		lm := LintMetric{
+			FQName:     extractFqName(m.Desc().String()),
+			Help:       extractHelp(m.Desc().String()),
+			Type:       metricType,
+			LabelNames: labelNames,
+		}
+		for _, problem := range linter.Lint(lm) {
+			t.Errorf("lint: %s", problem)
+		}
+	}
+}
+
+// collectDynamicMetrics runs the UncheckedCollector over jsonData using the
+// mapping config at mappingFile, returning sorted, deterministic output in
+// the same text format formatMetric produces.
+func collectDynamicMetrics(t *testing.T, jsonData []byte, mappingFile string) string {
+	t.Helper()
+	mapping, err := LoadDynamicMappingConfig(mappingFile)
+	if err != nil {
+		t.Fatalf("loading mapping config %s: %v", mappingFile, err)
 	}
-	s := descStr[i+len(prefix):]
-	j := strings.Index(s, `"`)
-	if j < 0 {
-		return "unknown"
+
+	json := gjson.Parse(string(jsonData))
+	ch := make(chan prometheus.Metric, 10000)
+	collector := NewUncheckedCollector(slog.Default(), json, mapping, nil)
+	collector.Collect(ch)
+	close(ch)
+
+	var lines []string
+	for m := range ch {
+		metric := &dto.Metric{}
+		if err := m.Write(metric); err != nil {
+			t.Fatalf("failed to write dynamic metric: %v", err)
+		}
+		lines = append(lines, formatMetric(m.Desc(), metric))
 	}
-	return s[:j]
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n"
 }
 
 // diff produces a simple line-by-line diff between want and got.
@@ -164,6 +310,7 @@ func TestGoldenFiles(t *testing.T) {
 	for _, jsonFile := range jsonFiles {
 		basename := filepath.Base(jsonFile)
 		goldenFile := filepath.Join("testdata", "golden", strings.TrimSuffix(basename, ".json")+".golden")
+		rwGoldenFile := filepath.Join("testdata", "golden", strings.TrimSuffix(basename, ".json")+".rw.golden")
 
 		t.Run(basename, func(t *testing.T) {
 			t.Parallel()
@@ -173,7 +320,11 @@ func TestGoldenFiles(t *testing.T) {
 				t.Fatalf("reading %s: %v", jsonFile, err)
 			}
 
-			got := collectMetrics(t, data)
+			_, metrics := collectRawMetrics(t, data)
+			lintMetrics(t, metrics)
+
+			got := formatMetrics(t, metrics)
+			gotRW := formatRemoteWriteSeries(t, metrics)
 
 			if *updateGolden {
 				if err := os.MkdirAll(filepath.Join("testdata", "golden"), 0755); err != nil {
@@ -182,6 +333,9 @@ func TestGoldenFiles(t *testing.T) {
 				if err := os.WriteFile(goldenFile, []byte(got), 0644); err != nil {
 					t.Fatalf("writing golden file: %v", err)
 				}
+				if err := os.WriteFile(rwGoldenFile, []byte(gotRW), 0644); err != nil {
+					t.Fatalf("writing remote write golden file: %v", err)
+				}
 				return
 			}
 
@@ -189,10 +343,67 @@ func TestGoldenFiles(t *testing.T) {
 			if err != nil {
 				t.Fatalf("reading golden file %s: %v (run with -update to create)", goldenFile, err)
 			}
-
 			if string(want) != got {
 				t.Errorf("output mismatch for %s:\n%s", basename, diff(string(want), got))
 			}
+
+			wantRW, err := os.ReadFile(rwGoldenFile)
+			if err != nil {
+				t.Fatalf("reading remote write golden file %s: %v (run with -update to create)", rwGoldenFile, err)
+			}
+			if string(wantRW) != gotRW {
+				t.Errorf("remote write output mismatch for %s:\n%s", basename, diff(string(wantRW), gotRW))
+			}
+		})
+	}
+}
+
+// TestDynamicMetricsGoldenFiles exercises UncheckedCollector against every
+// testdata/dynamic/*.json fixture using its sibling *.mapping.yaml. These
+// fixtures live under testdata/dynamic/ rather than testdata/ so
+// TestGoldenFiles' testdata/*.json glob
+// (the pull-path/remote-write golden pipeline) doesn't also pick them up.
+func TestDynamicMetricsGoldenFiles(t *testing.T) {
+	jsonFiles, err := filepath.Glob("testdata/dynamic/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jsonFiles) == 0 {
+		t.Fatal("no testdata/dynamic/*.json files found")
+	}
+
+	for _, jsonFile := range jsonFiles {
+		basename := filepath.Base(jsonFile)
+		mappingFile := filepath.Join("testdata", "dynamic", strings.TrimSuffix(basename, ".json")+".mapping.yaml")
+		goldenFile := filepath.Join("testdata", "dynamic", "golden", strings.TrimSuffix(basename, ".json")+".golden")
+
+		t.Run(basename, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(jsonFile)
+			if err != nil {
+				t.Fatalf("reading %s: %v", jsonFile, err)
+			}
+
+			got := collectDynamicMetrics(t, data, mappingFile)
+
+			if *updateGolden {
+				if err := os.MkdirAll(filepath.Join("testdata", "dynamic", "golden"), 0755); err != nil {
+					t.Fatalf("creating golden dir: %v", err)
+				}
+				if err := os.WriteFile(goldenFile, []byte(got), 0644); err != nil {
+					t.Fatalf("writing dynamic golden file: %v", err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("reading dynamic golden file %s: %v (run with -update to create)", goldenFile, err)
+			}
+			if string(want) != got {
+				t.Errorf("dynamic output mismatch for %s:\n%s", basename, diff(string(want), got))
+			}
 		})
 	}
 }
@@ -0,0 +1,180 @@
+// Copyright 2024 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+)
+
+// DynamicMetricMapping describes how to turn one field of the smartctl JSON
+// into a Prometheus metric: where to find the value (Path, a gjson
+// expression), what to call it and what shape it has.
+//
+// Path may resolve to a scalar, or to an array. For an array of scalars
+// (e.g. nvme_smart_health_information_log.temperature_sensors[]), each
+// element is the metric value directly. For an array of objects (e.g.
+// ata_smart_attributes.table[]), ValuePath and LabelPaths are gjson paths
+// evaluated relative to each element, so one sub-field (say "raw.value")
+// can be projected as the metric value while another (say "name")
+// becomes a label.
+type DynamicMetricMapping struct {
+	Path   string            `yaml:"path"`
+	Metric string            `yaml:"metric"`
+	Help   string            `yaml:"help"`
+	Type   string            `yaml:"type"` // "gauge", "counter", or "untyped"
+	Labels map[string]string `yaml:"labels"`
+	// ValuePath, for an array-of-objects Path, is the path to the metric
+	// value relative to each element. Ignored for scalar Path/elements,
+	// where the element itself is the value.
+	ValuePath string `yaml:"value_path"`
+	// LabelPaths, for an array-of-objects Path, maps label name to a path
+	// relative to each element whose string value becomes that label's
+	// value -- e.g. {"attribute": "name"}.
+	LabelPaths map[string]string `yaml:"label_paths"`
+	// NameLabel, if set, adds a label with this name whose value is the
+	// array index the sample was found at -- e.g. "sensor" for nvme
+	// temperature_sensors[] entries. For a Path that is not an array the
+	// label's value is empty; object keys are not used.
+	NameLabel string `yaml:"name_label"`
+}
+
+// DynamicMappingConfig is the root of a mapping YAML file, letting operators
+// enable vendor- or device-specific SMART attributes without recompiling.
+type DynamicMappingConfig struct {
+	Mappings []DynamicMetricMapping `yaml:"mappings"`
+}
+
+// LoadDynamicMappingConfig reads and parses a mapping YAML file from disk.
+func LoadDynamicMappingConfig(path string) (*DynamicMappingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mapping config %s: %w", path, err)
+	}
+	var cfg DynamicMappingConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing mapping config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// UncheckedCollector discovers metrics at runtime from the smartctl JSON
+// according to a DynamicMappingConfig, rather than registering a fixed
+// descriptor set like SMARTctl does. Its Describe sends no descriptors, as
+// permitted by the prometheus.Collector contract for "unchecked" collectors
+// -- this is required because the set of metrics it emits depends on which
+// vendor/device fields the mapping config and the JSON payload agree on.
+//
+// NOTE: loading a DynamicMappingConfig from a flag/config option and
+// registering an UncheckedCollector alongside SMARTctl in main is follow-up
+// work and not part of this change; today it's exercised only by
+// TestDynamicMetricsGoldenFiles.
+type UncheckedCollector struct {
+	logger      *slog.Logger
+	json        gjson.Result
+	mapping     *DynamicMappingConfig
+	extraLabels map[string]string
+}
+
+// NewUncheckedCollector builds an UncheckedCollector over json using the
+// given mapping config. extraLabels (e.g. device/model) are attached to
+// every metric this collector emits, mirroring the labels SMARTctl attaches
+// to its fixed descriptors.
+func NewUncheckedCollector(logger *slog.Logger, json gjson.Result, mapping *DynamicMappingConfig, extraLabels map[string]string) *UncheckedCollector {
+	return &UncheckedCollector{logger: logger, json: json, mapping: mapping, extraLabels: extraLabels}
+}
+
+// Describe intentionally sends no descriptors: this collector is unchecked,
+// so registering it does not require Prometheus to know its metrics ahead
+// of time.
+func (u *UncheckedCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect walks every configured mapping, resolving its gjson path against
+// the device JSON and emitting one metric per match.
+func (u *UncheckedCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range u.mapping.Mappings {
+		result := u.json.Get(m.Path)
+		if !result.Exists() {
+			continue
+		}
+
+		if result.IsArray() {
+			result.ForEach(func(key, value gjson.Result) bool {
+				u.emit(ch, m, key.String(), value)
+				return true
+			})
+			continue
+		}
+		u.emit(ch, m, "", result)
+	}
+}
+
+// emit builds and sends one metric for a single matched JSON element.
+// element is either the value itself (scalar Path, or an array of scalars)
+// or one entry of an array of objects, in which case m.ValuePath/LabelPaths
+// are resolved relative to it.
+func (u *UncheckedCollector) emit(ch chan<- prometheus.Metric, m DynamicMetricMapping, nameLabelValue string, element gjson.Result) {
+	value := element
+	if element.IsObject() {
+		if m.ValuePath == "" {
+			u.logger.Warn("skipping object dynamic metric value with no value_path", "metric", m.Metric, "path", m.Path)
+			return
+		}
+		value = element.Get(m.ValuePath)
+	}
+	if !value.Exists() || (!value.IsNumber() && !value.IsBool()) {
+		u.logger.Warn("skipping non-numeric dynamic metric value", "metric", m.Metric, "path", m.Path)
+		return
+	}
+
+	labelNames := make([]string, 0, len(m.Labels)+len(m.LabelPaths)+len(u.extraLabels)+1)
+	labelValues := make([]string, 0, cap(labelNames))
+	for k, v := range u.extraLabels {
+		labelNames = append(labelNames, k)
+		labelValues = append(labelValues, v)
+	}
+	for k, v := range m.Labels {
+		labelNames = append(labelNames, k)
+		labelValues = append(labelValues, v)
+	}
+	for name, path := range m.LabelPaths {
+		labelNames = append(labelNames, name)
+		labelValues = append(labelValues, element.Get(path).String())
+	}
+	if m.NameLabel != "" {
+		labelNames = append(labelNames, m.NameLabel)
+		labelValues = append(labelValues, nameLabelValue)
+	}
+
+	desc := prometheus.NewDesc(m.Metric, m.Help, labelNames, nil)
+	valueType := prometheus.UntypedValue
+	switch m.Type {
+	case "gauge":
+		valueType = prometheus.GaugeValue
+	case "counter":
+		valueType = prometheus.CounterValue
+	}
+
+	metric, err := prometheus.NewConstMetric(desc, valueType, value.Float(), labelValues...)
+	if err != nil {
+		u.logger.Warn("building dynamic metric", "metric", m.Metric, "err", err)
+		return
+	}
+	ch <- metric
+}